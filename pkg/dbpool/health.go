@@ -0,0 +1,70 @@
+package dbpool
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+)
+
+// HealthChecker periodically pings a *sql.DB in the background and keeps
+// track of the most recent result, so the rest of the app can ask "is the
+// database reachable?" without blocking on a ping itself.
+type HealthChecker struct {
+	db       *sql.DB
+	interval time.Duration
+
+	mu      sync.RWMutex
+	lastErr error
+}
+
+// NewHealthChecker builds a HealthChecker that pings db every interval once
+// Run is called.
+func NewHealthChecker(db *sql.DB, interval time.Duration) *HealthChecker {
+	return &HealthChecker{db: db, interval: interval}
+}
+
+// Run blocks, pinging the database every interval until ctx is cancelled.
+// Call it in its own goroutine: go checker.Run(ctx).
+func (h *HealthChecker) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.ping(ctx)
+		}
+	}
+}
+
+func (h *HealthChecker) ping(ctx context.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx, h.interval)
+	defer cancel()
+
+	err := h.db.PingContext(pingCtx)
+
+	h.mu.Lock()
+	h.lastErr = err
+	h.mu.Unlock()
+
+	if err != nil {
+		log.Println("dbpool: health check failed:", err)
+	}
+}
+
+// Err returns the error from the most recent ping, or nil if the database
+// was reachable (or no ping has run yet).
+func (h *HealthChecker) Err() error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.lastErr
+}
+
+// Stats returns the pool's current sql.DBStats.
+func (h *HealthChecker) Stats() sql.DBStats {
+	return h.db.Stats()
+}
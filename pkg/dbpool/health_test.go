@@ -0,0 +1,29 @@
+package dbpool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestHealthChecker_RecordsPingResult(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPing()
+
+	checker := NewHealthChecker(db, time.Hour)
+	checker.ping(context.Background())
+
+	if err := checker.Err(); err != nil {
+		t.Fatalf("Err() = %v, want nil after a successful ping", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package dbpool
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// LeakDetector wraps a *sql.DB so every *sql.Rows it returns is watched: if
+// the caller doesn't Close it within timeout, that's almost always a
+// forgotten "defer rows.Close()" slowly exhausting the pool until postgres
+// starts rejecting new connections with "too many connections for role".
+// It's meant for development, not production - hence debug must be set
+// explicitly to arm it, and a leak panics loudly rather than just logging.
+type LeakDetector struct {
+	db      *sql.DB
+	timeout time.Duration
+	debug   bool
+}
+
+// NewLeakDetector wraps db. debug must be true for leaks to actually panic;
+// with debug false, QueryContext behaves exactly like the underlying
+// *sql.DB (the wrapping is a no-op), so it's safe to leave in place and
+// flip on only when chasing a suspected leak.
+func NewLeakDetector(db *sql.DB, timeout time.Duration, debug bool) *LeakDetector {
+	return &LeakDetector{db: db, timeout: timeout, debug: debug}
+}
+
+func (l *LeakDetector) QueryContext(ctx context.Context, query string, args ...interface{}) (*trackedRows, error) {
+	rows, err := l.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return l.track(rows, query), nil
+}
+
+func (l *LeakDetector) track(rows *sql.Rows, query string) *trackedRows {
+	tr := &trackedRows{Rows: rows}
+	if l.debug {
+		tr.timer = time.AfterFunc(l.timeout, func() {
+			panic(fmt.Sprintf("dbpool: rows from query %q not closed within %s (leak detected)", query, l.timeout))
+		})
+	}
+	return tr
+}
+
+// trackedRows wraps *sql.Rows, promoting Next/Scan/Err/etc. unchanged, but
+// overrides Close to stop the leak timer before it fires.
+type trackedRows struct {
+	*sql.Rows
+	timer *time.Timer
+}
+
+func (t *trackedRows) Close() error {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	return t.Rows.Close()
+}
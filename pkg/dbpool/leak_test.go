@@ -0,0 +1,54 @@
+package dbpool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLeakDetector_ClosePromptlyStopsTimer(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"x"}).AddRow(1))
+
+	ld := NewLeakDetector(db, 20*time.Millisecond, true)
+	rows, err := ld.QueryContext(context.Background(), "SELECT 1")
+	if err != nil {
+		t.Fatalf("QueryContext returned error: %v", err)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+
+	// Give the timer a chance to fire; it must not, since Close already
+	// stopped it - a leaked-rows panic here would crash the test binary.
+	time.Sleep(40 * time.Millisecond)
+}
+
+func TestLeakDetector_DisabledIsNoop(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"x"}).AddRow(1))
+
+	ld := NewLeakDetector(db, time.Millisecond, false)
+	rows, err := ld.QueryContext(context.Background(), "SELECT 1")
+	if err != nil {
+		t.Fatalf("QueryContext returned error: %v", err)
+	}
+	if rows.timer != nil {
+		t.Errorf("expected no timer to be armed when debug is false")
+	}
+
+	// No Close() call on purpose: with debug off this must never panic.
+	time.Sleep(10 * time.Millisecond)
+}
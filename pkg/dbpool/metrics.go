@@ -0,0 +1,55 @@
+package dbpool
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// MetricsHandler renders h.Stats() (plus the last health-check result) as
+// Prometheus text exposition format, suitable for mounting at /metrics:
+//
+//	mux.Handle("/metrics", checker.MetricsHandler())
+func (h *HealthChecker) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stats := h.Stats()
+
+		healthy := 1
+		if h.Err() != nil {
+			healthy = 0
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+		fmt.Fprintln(w, "# HELP db_healthy Whether the last health check ping succeeded.")
+		fmt.Fprintln(w, "# TYPE db_healthy gauge")
+		fmt.Fprintf(w, "db_healthy %d\n", healthy)
+
+		fmt.Fprintln(w, "# HELP db_open_connections Number of established connections, both in use and idle.")
+		fmt.Fprintln(w, "# TYPE db_open_connections gauge")
+		fmt.Fprintf(w, "db_open_connections %d\n", stats.OpenConnections)
+
+		fmt.Fprintln(w, "# HELP db_in_use Number of connections currently in use.")
+		fmt.Fprintln(w, "# TYPE db_in_use gauge")
+		fmt.Fprintf(w, "db_in_use %d\n", stats.InUse)
+
+		fmt.Fprintln(w, "# HELP db_idle Number of idle connections.")
+		fmt.Fprintln(w, "# TYPE db_idle gauge")
+		fmt.Fprintf(w, "db_idle %d\n", stats.Idle)
+
+		fmt.Fprintln(w, "# HELP db_wait_count Total number of connections waited for.")
+		fmt.Fprintln(w, "# TYPE db_wait_count counter")
+		fmt.Fprintf(w, "db_wait_count %d\n", stats.WaitCount)
+
+		fmt.Fprintln(w, "# HELP db_wait_duration_seconds_total Total time blocked waiting for a new connection.")
+		fmt.Fprintln(w, "# TYPE db_wait_duration_seconds_total counter")
+		fmt.Fprintf(w, "db_wait_duration_seconds_total %f\n", stats.WaitDuration.Seconds())
+
+		fmt.Fprintln(w, "# HELP db_max_idle_closed_total Total connections closed due to SetMaxIdleConns.")
+		fmt.Fprintln(w, "# TYPE db_max_idle_closed_total counter")
+		fmt.Fprintf(w, "db_max_idle_closed_total %d\n", stats.MaxIdleClosed)
+
+		fmt.Fprintln(w, "# HELP db_max_lifetime_closed_total Total connections closed due to SetConnMaxLifetime.")
+		fmt.Fprintln(w, "# TYPE db_max_lifetime_closed_total counter")
+		fmt.Fprintf(w, "db_max_lifetime_closed_total %d\n", stats.MaxLifetimeClosed)
+	})
+}
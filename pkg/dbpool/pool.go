@@ -0,0 +1,50 @@
+// Package dbpool wires *sql.DB's connection pool limits, runs a background
+// health-check loop against it, exposes its stats on a /metrics endpoint in
+// Prometheus text format, and offers a rows leak detector for development
+// use - the things sql.Open alone leaves to the caller.
+package dbpool
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PoolConfig holds the pool limits sql.Open doesn't set for you. Zero
+// values are left untouched (database/sql's own unlimited defaults apply)
+// except where noted.
+type PoolConfig struct {
+	// MaxOpenConns caps the total number of open connections (idle + in
+	// use). 0 means unlimited, matching database/sql's default.
+	MaxOpenConns int
+	// MaxIdleConns caps how many idle connections are kept around for
+	// reuse. 0 falls back to database/sql's default of 2.
+	MaxIdleConns int
+	// ConnMaxLifetime is the longest a connection may be reused before
+	// it's closed and replaced. 0 means connections are reused forever.
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime is the longest a connection may sit idle before
+	// it's closed. 0 means idle connections are never closed for being
+	// idle.
+	ConnMaxIdleTime time.Duration
+}
+
+// DefaultPoolConfig returns sane defaults for a small service talking to a
+// single postgres instance.
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		MaxOpenConns:    25,
+		MaxIdleConns:    25,
+		ConnMaxLifetime: 5 * time.Minute,
+		ConnMaxIdleTime: 5 * time.Minute,
+	}
+}
+
+// Apply wires c's limits onto db via the corresponding SetXxx calls.
+func (c PoolConfig) Apply(db *sql.DB) {
+	db.SetMaxOpenConns(c.MaxOpenConns)
+	if c.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(c.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(c.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+}
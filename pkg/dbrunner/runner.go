@@ -0,0 +1,178 @@
+// Package dbrunner provides Runner, a thin wrapper around *sql.DB that
+// routes every call through the *Context variants (QueryContext,
+// ExecContext, QueryRowContext), applies a per-call deadline, and retries
+// transient postgres errors with exponential backoff. pkg/users holds a
+// *Runner instead of a *sql.DB; pkg/store's sql backend reaches it through
+// pkg/users for its CRUD calls.
+package dbrunner
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgconn"
+)
+
+// Serialization failure and deadlock_detected SQLSTATEs - see
+// https://www.postgresql.org/docs/current/errcodes-appendix.html. Both are
+// safe to retry because postgres guarantees the transaction had no visible
+// effect when either occurs.
+const (
+	sqlstateSerializationFailure = "40001"
+	sqlstateDeadlockDetected     = "40P01"
+)
+
+// Option configures a Runner at construction time.
+type Option func(*Runner)
+
+// WithTimeout sets the deadline applied to every call. Default: 5s.
+func WithTimeout(d time.Duration) Option {
+	return func(r *Runner) { r.timeout = d }
+}
+
+// WithMaxRetries sets how many times a retryable error is retried before
+// giving up. Default: 3.
+func WithMaxRetries(n int) Option {
+	return func(r *Runner) { r.maxRetries = n }
+}
+
+// WithBackoff sets the initial delay before the first retry; it doubles on
+// each subsequent attempt. Default: 50ms.
+func WithBackoff(d time.Duration) Option {
+	return func(r *Runner) { r.backoff = d }
+}
+
+// Runner runs queries against a *sql.DB with a deadline and a
+// retry-on-transient-error policy applied uniformly.
+type Runner struct {
+	db         *sql.DB
+	timeout    time.Duration
+	maxRetries int
+	backoff    time.Duration
+}
+
+// New wraps db in a Runner, applying any Options given.
+func New(db *sql.DB, opts ...Option) *Runner {
+	r := &Runner{
+		db:         db,
+		timeout:    5 * time.Second,
+		maxRetries: 3,
+		backoff:    50 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// QueryContext, ExecContext and QueryRowContext below match the signatures
+// squirrel's RunWith() looks for (ExecerContext/QueryerContext/
+// QueryRowerContext), so a *Runner can be passed straight to
+// sq.StatementBuilderType.RunWith and used via .QueryContext/.ExecContext/
+// .QueryRowContext. Exec, Query and QueryRow (no Context suffix) satisfy
+// squirrel.BaseRunner, which RunWith itself is typed to accept - they're
+// never actually called since every query in this repo goes through a
+// context, but the type has to be there for RunWith to compile against.
+
+func (r *Runner) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return r.db.Exec(query, args...)
+}
+
+func (r *Runner) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return r.db.Query(query, args...)
+}
+
+func (r *Runner) QueryRow(query string, args ...interface{}) sq.RowScanner {
+	return r.db.QueryRow(query, args...)
+}
+
+func (r *Runner) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := r.withRetry(ctx, func(ctx context.Context) error {
+		var err error
+		rows, err = r.db.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+func (r *Runner) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var res sql.Result
+	err := r.withRetry(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = r.db.ExecContext(ctx, query, args...)
+		return err
+	})
+	return res, err
+}
+
+// QueryRowContext applies the Runner's deadline but does not retry: a
+// *sql.Row defers its error until Scan is called, so there's no error here
+// yet to decide retryability on. The return type is squirrel.RowScanner
+// rather than *sql.Row so that *Runner satisfies QueryRowerContext (which
+// squirrel type-asserts for at runtime) - *sql.Row already has a matching
+// Scan method, so this is a free upcast.
+func (r *Runner) QueryRowContext(ctx context.Context, query string, args ...interface{}) sq.RowScanner {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	time.AfterFunc(r.timeout, cancel)
+	return r.db.QueryRowContext(ctx, query, args...)
+}
+
+// withRetry runs fn, retrying on a retryable error with exponential backoff
+// up to maxRetries times. Each attempt gets its own deadline derived from
+// ctx; constraint violations and other non-transient errors are returned
+// immediately.
+//
+// callCtx's cancel is deferred via time.AfterFunc rather than called right
+// after fn returns: fn may hand back a *sql.Rows/*sql.Row that's still tied
+// to callCtx's lifetime (database/sql watches the context for the life of
+// the Rows), so cancelling synchronously here raced with the caller still
+// reading it, surfacing as spurious "context canceled" errors under any
+// real latency. Scheduling the cancel for when the deadline would have
+// fired anyway releases callCtx's resources without that race.
+func (r *Runner) withRetry(ctx context.Context, fn func(context.Context) error) error {
+	backoff := r.backoff
+
+	var lastErr error
+	for attempt := 0; attempt <= r.maxRetries; attempt++ {
+		callCtx, cancel := context.WithTimeout(ctx, r.timeout)
+		time.AfterFunc(r.timeout, cancel)
+		err := fn(callCtx)
+
+		if err == nil {
+			return nil
+		}
+		if !IsRetryable(err) {
+			return err
+		}
+		lastErr = err
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+	return lastErr
+}
+
+// IsRetryable reports whether err is a transient postgres error worth
+// retrying (serialization failure or deadlock), as opposed to e.g. a
+// constraint violation (23xxx) which will just fail again. pkg/tx reuses
+// this to give WithTx the same serializable-retry semantics.
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	switch pgErr.Code {
+	case sqlstateSerializationFailure, sqlstateDeadlockDetected:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,93 @@
+package dbrunner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgconn"
+)
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"serialization failure", &pgconn.PgError{Code: sqlstateSerializationFailure}, true},
+		{"deadlock detected", &pgconn.PgError{Code: sqlstateDeadlockDetected}, true},
+		{"constraint violation", &pgconn.PgError{Code: "23505"}, false},
+		{"non-pg error", errors.New("boom"), false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsRetryable(tc.err); got != tc.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRunner_ExecContext_RetriesOnSerializationFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE users").WillReturnError(&pgconn.PgError{Code: sqlstateSerializationFailure})
+	mock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	r := New(db, WithBackoff(time.Millisecond))
+	_, err = r.ExecContext(context.Background(), "UPDATE users SET first_name = $1 WHERE id = $2", "Jackie", 5)
+	if err != nil {
+		t.Fatalf("ExecContext returned error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestRunner_ExecContext_ExhaustsRetriesOnPersistentFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	pgErr := &pgconn.PgError{Code: sqlstateDeadlockDetected}
+	mock.ExpectExec("UPDATE users").WillReturnError(pgErr)
+	mock.ExpectExec("UPDATE users").WillReturnError(pgErr)
+
+	r := New(db, WithMaxRetries(1), WithBackoff(time.Millisecond))
+	_, err = r.ExecContext(context.Background(), "UPDATE users SET first_name = $1 WHERE id = $2", "Jackie", 5)
+	if !errors.Is(err, pgErr) {
+		t.Fatalf("ExecContext error = %v, want %v", err, pgErr)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestRunner_ExecContext_NoRetryOnConstraintViolation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	pgErr := &pgconn.PgError{Code: "23505"}
+	mock.ExpectExec("INSERT INTO users").WillReturnError(pgErr)
+
+	r := New(db, WithBackoff(time.Millisecond))
+	_, err = r.ExecContext(context.Background(), "INSERT INTO users (first_name) VALUES ($1)", "Jack")
+	if !errors.Is(err, pgErr) {
+		t.Fatalf("ExecContext error = %v, want %v", err, pgErr)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
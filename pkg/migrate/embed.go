@@ -0,0 +1,11 @@
+package migrate
+
+import "embed"
+
+// FS embeds the numbered .sql migrations this module ships - currently just
+// 0001_create_users, which is what lets `go run main.go` bootstrap the
+// users table on a brand new database instead of requiring it to be
+// created by hand first.
+//
+//go:embed sql/*.sql
+var FS embed.FS
@@ -0,0 +1,301 @@
+// Package migrate applies numbered .sql migrations embedded into the
+// binary via embed.FS, tracking which have run in a schema_migrations
+// table and using pg_advisory_lock so two instances starting up at once
+// don't race to apply the same migration twice.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// advisoryLockKey is an arbitrary, app-specific key for pg_advisory_lock.
+// Any int64 works as long as every instance of this app uses the same one.
+const advisoryLockKey = 72710001
+
+// unlockTimeout bounds the pg_advisory_unlock call made on the way out of
+// withLock. It deliberately does not inherit the caller's ctx - see the
+// comment on withLock.
+const unlockTimeout = 5 * time.Second
+
+var migrationFilename = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// migration is one numbered migration, paired up from its .up.sql and
+// .down.sql files.
+type migration struct {
+	version int64
+	name    string
+	upSQL   string
+	downSQL string
+}
+
+// Status describes one migration's applied state.
+type Status struct {
+	Version int64
+	Name    string
+	Applied bool
+}
+
+// Migrator applies migrations read from fsys (e.g. migrate.FS) under dir
+// (e.g. "sql") against db.
+type Migrator struct {
+	db   *sql.DB
+	fsys fs.FS
+	dir  string
+}
+
+// New builds a Migrator. fsys is typically migrate.FS and dir "sql"; they're
+// parameters rather than hardcoded so callers can embed and ship their own
+// migrations through the same Migrator.
+func New(db *sql.DB, fsys fs.FS, dir string) *Migrator {
+	return &Migrator{db: db, fsys: fsys, dir: dir}
+}
+
+// Up applies every migration that hasn't run yet, in version order.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(ctx context.Context) error {
+		migrations, err := m.loadMigrations()
+		if err != nil {
+			return err
+		}
+		applied, err := m.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range migrations {
+			if applied[mig.version] {
+				continue
+			}
+			if err := m.apply(ctx, mig, mig.upSQL); err != nil {
+				return fmt.Errorf("migrate: applying %d_%s: %w", mig.version, mig.name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Down rolls back the single most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.Steps(ctx, -1)
+}
+
+// Steps applies the next n pending migrations in ascending version order
+// (n > 0) or rolls back the last |n| applied migrations, newest first
+// (n < 0). n == 0 is a no-op.
+func (m *Migrator) Steps(ctx context.Context, n int) error {
+	return m.withLock(ctx, func(ctx context.Context) error {
+		migrations, err := m.loadMigrations()
+		if err != nil {
+			return err
+		}
+		applied, err := m.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+
+		if n > 0 {
+			for _, mig := range migrations {
+				if n == 0 {
+					break
+				}
+				if applied[mig.version] {
+					continue
+				}
+				if err := m.apply(ctx, mig, mig.upSQL); err != nil {
+					return fmt.Errorf("migrate: applying %d_%s: %w", mig.version, mig.name, err)
+				}
+				n--
+			}
+			return nil
+		}
+
+		for i := len(migrations) - 1; i >= 0 && n < 0; i-- {
+			mig := migrations[i]
+			if !applied[mig.version] {
+				continue
+			}
+			if err := m.revert(ctx, mig); err != nil {
+				return fmt.Errorf("migrate: reverting %d_%s: %w", mig.version, mig.name, err)
+			}
+			n++
+		}
+		return nil
+	})
+}
+
+// Status reports every known migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	migrations, err := m.loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.ensureSchemaTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Status, len(migrations))
+	for i, mig := range migrations {
+		out[i] = Status{Version: mig.version, Name: mig.name, Applied: applied[mig.version]}
+	}
+	return out, nil
+}
+
+func (m *Migrator) apply(ctx context.Context, mig migration, sqlText string) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, sqlText); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO schema_migrations (version) VALUES ($1)`, mig.version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) revert(ctx context.Context, mig migration) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, mig.downSQL); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM schema_migrations WHERE version = $1`, mig.version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) ensureSchemaTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    bigint PRIMARY KEY,
+			applied_at timestamptz NOT NULL DEFAULT now()
+		)`)
+	return err
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context) (map[int64]bool, error) {
+	if err := m.ensureSchemaTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var v int64
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+// withLock serializes fn across every instance of the app talking to the
+// same database, via pg_advisory_lock - so two processes starting up
+// concurrently don't both try to apply the same migration. pg_advisory_lock
+// and pg_advisory_unlock are session-scoped: they must run on the same
+// physical connection, so this pins the lock and unlock calls to a single
+// *sql.Conn checked out from the pool rather than routing each call through
+// m.db, which could hand each one a different connection and leave the
+// lock held forever on a connection nothing ever unlocks again. fn's own
+// queries still go through m.db.
+func (m *Migrator) withLock(ctx context.Context, fn func(context.Context) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockKey); err != nil {
+		return err
+	}
+	defer func() {
+		// Deliberately not ctx: conn.Close() only returns the *sql.Conn to
+		// the pool rather than closing the physical connection, and if ctx
+		// is already cancelled/expired by the time fn returns (callers are
+		// free to bound Up/Steps with a deadline), this ExecContext would
+		// fail without ever unlocking - leaving the session-scoped
+		// advisory lock held on a pooled connection nothing will release,
+		// and every future withLock call blocking on it forever.
+		unlockCtx, cancel := context.WithTimeout(context.Background(), unlockTimeout)
+		defer cancel()
+		if _, err := conn.ExecContext(unlockCtx, `SELECT pg_advisory_unlock($1)`, advisoryLockKey); err != nil {
+			log.Printf("migrate: failed to release advisory lock %d: %v", advisoryLockKey, err)
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// loadMigrations reads every *.up.sql/*.down.sql pair from m.dir and
+// returns them sorted by version.
+func (m *Migrator) loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(m.fsys, m.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*migration)
+	for _, entry := range entries {
+		match := migrationFilename.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		var version int64
+		if _, err := fmt.Sscanf(match[1], "%d", &version); err != nil {
+			return nil, fmt.Errorf("migrate: invalid version in %q: %w", entry.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &migration{version: version, name: match[2]}
+			byVersion[version] = mig
+		}
+
+		contents, err := fs.ReadFile(m.fsys, m.dir+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		switch match[3] {
+		case "up":
+			mig.upSQL = string(contents)
+		case "down":
+			mig.downSQL = string(contents)
+		}
+	}
+
+	out := make([]migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		out = append(out, *mig)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].version < out[j].version })
+	return out, nil
+}
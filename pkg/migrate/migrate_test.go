@@ -0,0 +1,210 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	m := New(nil, FS, "sql")
+
+	migrations, err := m.loadMigrations()
+	if err != nil {
+		t.Fatalf("loadMigrations() returned error: %v", err)
+	}
+	if len(migrations) != 1 {
+		t.Fatalf("expected 1 migration, got %d", len(migrations))
+	}
+
+	got := migrations[0]
+	if got.version != 1 {
+		t.Errorf("expected version 1, got %d", got.version)
+	}
+	if got.name != "create_users" {
+		t.Errorf("expected name %q, got %q", "create_users", got.name)
+	}
+	if !strings.Contains(got.upSQL, "CREATE TABLE users") {
+		t.Errorf("upSQL missing CREATE TABLE users: %q", got.upSQL)
+	}
+	if !strings.Contains(got.downSQL, "DROP TABLE users") {
+		t.Errorf("downSQL missing DROP TABLE users: %q", got.downSQL)
+	}
+}
+
+// testFS is a 3-migration fixture (independent of the single migration this
+// repo ships via FS) big enough to exercise ordering and partial-apply
+// behavior.
+var testFS = fstest.MapFS{
+	"sql/0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id serial PRIMARY KEY)")},
+	"sql/0001_create_users.down.sql": {Data: []byte("DROP TABLE users")},
+	"sql/0002_add_age.up.sql":        {Data: []byte("ALTER TABLE users ADD COLUMN age int")},
+	"sql/0002_add_age.down.sql":      {Data: []byte("ALTER TABLE users DROP COLUMN age")},
+	"sql/0003_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD COLUMN email text")},
+	"sql/0003_add_email.down.sql":    {Data: []byte("ALTER TABLE users DROP COLUMN email")},
+}
+
+// expectLockAcquire sets up the pg_advisory_lock call every withLock call
+// makes before running fn.
+func expectLockAcquire(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("SELECT pg_advisory_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+// expectLockRelease sets up the pg_advisory_unlock call withLock makes once
+// fn has returned - it's expected last, not right after the acquire, since
+// that's when it actually fires.
+func expectLockRelease(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+// expectAppliedVersions sets up ensureSchemaTable followed by the query
+// appliedVersions issues, returning the given already-applied versions.
+func expectAppliedVersions(mock sqlmock.Sqlmock, versions ...int64) {
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{"version"})
+	for _, v := range versions {
+		rows.AddRow(v)
+	}
+	mock.ExpectQuery("SELECT version FROM schema_migrations").WillReturnRows(rows)
+}
+
+// expectApply sets up the Begin/Exec(upSQL)/Exec(insert)/Commit sequence
+// Migrator.apply issues for one migration.
+func expectApply(mock sqlmock.Sqlmock, upSQL string) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexpQuote(upSQL)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+// expectRevert sets up the Begin/Exec(downSQL)/Exec(delete)/Commit sequence
+// Migrator.revert issues for one migration.
+func expectRevert(mock sqlmock.Sqlmock, downSQL string) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexpQuote(downSQL)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schema_migrations").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+// regexpQuote escapes the regexp metacharacters ( ) . * + ? in SQL text so
+// it can be used as a literal ExpectExec match; the SQL the tests below pass
+// through it contains none of them, but 0001's up SQL above has parentheses,
+// so being explicit keeps the helper safe for fixtures like it.
+func regexpQuote(s string) string {
+	r := strings.NewReplacer(
+		"(", `\(`, ")", `\)`,
+		".", `\.`, "*", `\*`, "+", `\+`, "?", `\?`,
+	)
+	return r.Replace(s)
+}
+
+func TestMigrator_Up_AppliesOnlyUnappliedInOrder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	expectLockAcquire(mock)
+	expectAppliedVersions(mock, 1)
+	expectApply(mock, "ALTER TABLE users ADD COLUMN age int")
+	expectApply(mock, "ALTER TABLE users ADD COLUMN email text")
+	expectLockRelease(mock)
+
+	m := New(db, testFS, "sql")
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatalf("Up() returned error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigrator_Steps_Positive(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	expectLockAcquire(mock)
+	expectAppliedVersions(mock, 1)
+	expectApply(mock, "ALTER TABLE users ADD COLUMN age int")
+	expectLockRelease(mock)
+
+	m := New(db, testFS, "sql")
+	if err := m.Steps(context.Background(), 1); err != nil {
+		t.Fatalf("Steps(1) returned error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigrator_Steps_Negative(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	expectLockAcquire(mock)
+	expectAppliedVersions(mock, 1, 2, 3)
+	expectRevert(mock, "ALTER TABLE users DROP COLUMN email")
+	expectLockRelease(mock)
+
+	m := New(db, testFS, "sql")
+	if err := m.Steps(context.Background(), -1); err != nil {
+		t.Fatalf("Steps(-1) returned error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// TestMigrator_WithLock_UnlocksOnSameConnectionDespiteCancelledCtx guards
+// against both regressions those follow-up fixes addressed: pg_advisory_lock
+// and pg_advisory_unlock must land on the same *sql.Conn (705b4df), and the
+// unlock must still succeed even if the caller's ctx is already cancelled by
+// the time fn returns (3312d61). sqlmock matches an ExecContext call against
+// its expectation queue before it even looks at ctx.Done(), so
+// ExpectationsWereMet alone can't tell a successful unlock from one that
+// fired ErrCancelled - only withLock's own failure log distinguishes them,
+// since the unlock error itself is never returned to the caller.
+func TestMigrator_WithLock_UnlocksOnSameConnectionDespiteCancelledCtx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	expectLockAcquire(mock)
+	expectLockRelease(mock)
+
+	m := New(db, testFS, "sql")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	err = m.withLock(ctx, func(ctx context.Context) error {
+		cancel()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("withLock returned error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+	if strings.Contains(logs.String(), "failed to release advisory lock") {
+		t.Errorf("advisory lock was not released (unlock used the caller's cancelled ctx): %s", logs.String())
+	}
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	_ "github.com/jackc/pgx/v4/stdlib"
+
+	"github.com/gustavNdamukong/golang-db-connect-demo/pkg/users"
+)
+
+// These benchmarks need a real postgres instance, so they read the DSN from
+// an env var and skip (rather than fail) when it isn't set - CI and most
+// dev machines won't have a scratch database sitting around.
+const dsnEnvVar = "STORE_BENCH_DSN"
+
+func benchRows(n int) []users.User {
+	rows := make([]users.User, n)
+	for i := range rows {
+		rows[i] = users.User{
+			FirstName: sql.NullString{String: "Bench", Valid: true},
+			LastName:  sql.NullString{String: "User", Valid: true},
+		}
+	}
+	return rows
+}
+
+func BenchmarkSQLStore_BulkInsert_ExecLoop(b *testing.B) {
+	dsn := os.Getenv(dsnEnvVar)
+	if dsn == "" {
+		b.Skipf("%s not set, skipping (needs a real postgres instance)", dsnEnvVar)
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		b.Fatalf("sql.Open: %v", err)
+	}
+	defer db.Close()
+
+	s := NewSQLStore(db)
+	rows := benchRows(1000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.BulkInsert(context.Background(), rows); err != nil {
+			b.Fatalf("BulkInsert: %v", err)
+		}
+	}
+}
+
+func BenchmarkPgxStore_BulkInsert_CopyFrom(b *testing.B) {
+	dsn := os.Getenv(dsnEnvVar)
+	if dsn == "" {
+		b.Skipf("%s not set, skipping (needs a real postgres instance)", dsnEnvVar)
+	}
+
+	pool, err := pgxpool.Connect(context.Background(), dsn)
+	if err != nil {
+		b.Fatalf("pgxpool.Connect: %v", err)
+	}
+	defer pool.Close()
+
+	s := NewPgxStore(pool)
+	rows := benchRows(1000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.BulkInsert(context.Background(), rows); err != nil {
+			b.Fatalf("BulkInsert: %v", err)
+		}
+	}
+}
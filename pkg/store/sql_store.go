@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/gustavNdamukong/golang-db-connect-demo/pkg/users"
+)
+
+// sqlStore is the Store implementation backed by database/sql, i.e. the
+// original pgx/v4/stdlib path. CRUD is delegated straight to
+// users.UserRepository; BulkInsert is the one operation this package adds
+// on top of it.
+type sqlStore struct {
+	db   *sql.DB
+	repo users.UserRepository
+}
+
+// NewSQLStore wraps a *sql.DB (opened with sql.Open("pgx", ...)) as a Store.
+func NewSQLStore(db *sql.DB) Store {
+	return &sqlStore{db: db, repo: users.NewRepository(db)}
+}
+
+func (s *sqlStore) List(ctx context.Context) ([]users.User, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *sqlStore) Get(ctx context.Context, id int) (users.User, error) {
+	return s.repo.Get(ctx, id)
+}
+
+func (s *sqlStore) Create(ctx context.Context, u users.User) (int, error) {
+	return s.repo.Create(ctx, u)
+}
+
+func (s *sqlStore) Update(ctx context.Context, u users.User) error {
+	return s.repo.Update(ctx, u)
+}
+
+func (s *sqlStore) Delete(ctx context.Context, id int) error {
+	return s.repo.Delete(ctx, id)
+}
+
+// BulkInsert runs one INSERT per row inside a single transaction. database/sql
+// has no bulk-load API of its own, so this is the best a sql.DB backend can
+// do - it's the baseline the pgxStore.BulkInsert benchmark is measured
+// against.
+func (s *sqlStore) BulkInsert(ctx context.Context, rows []users.User) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO users (first_name, last_name) VALUES ($1, $2)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var n int64
+	for _, u := range rows {
+		if _, err := stmt.ExecContext(ctx, u.FirstName, u.LastName); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, tx.Commit()
+}
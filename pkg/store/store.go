@@ -0,0 +1,33 @@
+// Package store exposes the CRUD surface the demo needs behind a single
+// Store interface, backed by two different drivers: a database/sql one
+// (pkg/users, via pgx/v4/stdlib) and a pgx-native one (pgxpool.Pool). The
+// two exist side by side so main.go can demonstrate the same flow on
+// whichever backend the -backend flag selects, and so pgx-only features
+// (CopyFrom, batching) have somewhere to live without forcing every caller
+// onto the pgx API.
+package store
+
+import (
+	"context"
+
+	"github.com/gustavNdamukong/golang-db-connect-demo/pkg/users"
+)
+
+// Store is the backend-agnostic CRUD surface main.go drives its demo
+// through. Both implementations in this package satisfy it. Every method
+// takes a context.Context, which each backend uses to bound and cancel the
+// underlying query - see pkg/dbrunner for how the sql backend also retries
+// transient errors within that context.
+type Store interface {
+	List(ctx context.Context) ([]users.User, error)
+	Get(ctx context.Context, id int) (users.User, error)
+	Create(ctx context.Context, u users.User) (int, error)
+	Update(ctx context.Context, u users.User) error
+	Delete(ctx context.Context, id int) error
+
+	// BulkInsert loads many rows and returns the number of rows written.
+	// The sql.DB backend does this as a looped Exec (one round trip per
+	// row) inside a single transaction; the pgx backend uses CopyFrom,
+	// which streams them all in one COPY.
+	BulkInsert(ctx context.Context, rows []users.User) (int64, error)
+}
@@ -0,0 +1,148 @@
+// Package tx provides a TxManager that runs a callback inside a database
+// transaction, nesting via SAVEPOINTs instead of nested BEGINs, and retries
+// the whole unit on a serializable/deadlock failure.
+package tx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/gustavNdamukong/golang-db-connect-demo/pkg/dbrunner"
+)
+
+// txStateKey is the context key WithTx stores the active transaction
+// under, so a nested WithTx call on the same ctx can find it.
+type txStateKey struct{}
+
+// txState is shared by a transaction and every nested savepoint within it.
+type txState struct {
+	tx  *sql.Tx
+	seq int32 // savepoint counter, bumped atomically as nesting happens
+}
+
+// Option configures a Manager at construction time.
+type Option func(*Manager)
+
+// WithIsolationLevel sets the isolation level used for the outermost
+// transaction of a WithTx call (nested calls are savepoints within it, so
+// this has no effect on them). Default: sql.LevelDefault.
+func WithIsolationLevel(level sql.IsolationLevel) Option {
+	return func(m *Manager) { m.isolation = level }
+}
+
+// WithMaxRetries sets how many times a whole transaction is retried after a
+// serializable/deadlock failure. Default: 3.
+func WithMaxRetries(n int) Option {
+	return func(m *Manager) { m.maxRetries = n }
+}
+
+// WithBackoff sets the initial delay before the first retry; it doubles on
+// each subsequent attempt. Default: 50ms.
+func WithBackoff(d time.Duration) Option {
+	return func(m *Manager) { m.backoff = d }
+}
+
+// Manager begins and commits/rolls back transactions for WithTx.
+type Manager struct {
+	db         *sql.DB
+	isolation  sql.IsolationLevel
+	maxRetries int
+	backoff    time.Duration
+}
+
+// NewManager wraps db (e.g. one opened with sql.Open("pgx", ...)) in a
+// Manager.
+func NewManager(db *sql.DB, opts ...Option) *Manager {
+	m := &Manager{
+		db:         db,
+		isolation:  sql.LevelDefault,
+		maxRetries: 3,
+		backoff:    50 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// WithTx runs fn inside a transaction, committing if it returns nil and
+// rolling back otherwise. fn is handed a ctx carrying the active
+// transaction, so that a nested WithTx(ctx, ...) call made inside fn is
+// recognised as nested and opens a SAVEPOINT instead of a new transaction.
+//
+// The outermost call additionally retries the whole unit, with exponential
+// backoff, if it fails on a serializable or deadlock error (SQLSTATE 40001
+// / 40P01) - see pkg/dbrunner.IsRetryable.
+func (m *Manager) WithTx(ctx context.Context, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	if state, ok := txStateFrom(ctx); ok {
+		return m.withSavepoint(ctx, state, fn)
+	}
+
+	return m.withRetry(ctx, func(ctx context.Context) error {
+		tx, err := m.db.BeginTx(ctx, &sql.TxOptions{Isolation: m.isolation})
+		if err != nil {
+			return err
+		}
+
+		txCtx := context.WithValue(ctx, txStateKey{}, &txState{tx: tx})
+
+		if err := fn(txCtx, tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+func (m *Manager) withSavepoint(ctx context.Context, state *txState, fn func(context.Context, *sql.Tx) error) error {
+	name := fmt.Sprintf("sp_%d", atomic.AddInt32(&state.seq, 1))
+
+	if _, err := state.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+
+	if err := fn(ctx, state.tx); err != nil {
+		if _, rbErr := state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return fmt.Errorf("%w (rollback to savepoint %s also failed: %v)", err, name, rbErr)
+		}
+		return err
+	}
+
+	_, err := state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+// withRetry runs fn (one full BEGIN..COMMIT/ROLLBACK attempt), retrying on
+// a serializable/deadlock error with exponential backoff up to maxRetries
+// times.
+func (m *Manager) withRetry(ctx context.Context, fn func(context.Context) error) error {
+	backoff := m.backoff
+
+	var lastErr error
+	for attempt := 0; attempt <= m.maxRetries; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if !dbrunner.IsRetryable(err) {
+			return err
+		}
+		lastErr = err
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+	return lastErr
+}
+
+func txStateFrom(ctx context.Context) (*txState, bool) {
+	state, ok := ctx.Value(txStateKey{}).(*txState)
+	return state, ok
+}
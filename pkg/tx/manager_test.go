@@ -0,0 +1,152 @@
+package tx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgconn"
+)
+
+func TestManager_WithTx_CommitsOnSuccess(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mgr := NewManager(db)
+	err = mgr.WithTx(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "UPDATE users SET first_name = $1 WHERE id = $2", "Jackie", 5)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx returned error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestManager_WithTx_RollsBackOnError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	mgr := NewManager(db)
+	wantErr := errors.New("boom")
+	err = mgr.WithTx(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		return wantErr
+	})
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("WithTx error = %v, want %v", err, wantErr)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestManager_WithTx_RetriesOnSerializationFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users").WillReturnError(&pgconn.PgError{Code: "40001"})
+	mock.ExpectRollback()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mgr := NewManager(db, WithBackoff(time.Millisecond))
+	attempts := 0
+	err = mgr.WithTx(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		attempts++
+		_, err := tx.ExecContext(ctx, "UPDATE users SET first_name = $1 WHERE id = $2", "Jackie", 5)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx returned error: %v", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("expected fn to run 2 times, ran %d", attempts)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestManager_WithTx_ExhaustsRetriesOnPersistentFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	pgErr := &pgconn.PgError{Code: "40P01"}
+	for i := 0; i < 2; i++ {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE users").WillReturnError(pgErr)
+		mock.ExpectRollback()
+	}
+
+	mgr := NewManager(db, WithMaxRetries(1), WithBackoff(time.Millisecond))
+	attempts := 0
+	err = mgr.WithTx(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		attempts++
+		_, err := tx.ExecContext(ctx, "UPDATE users SET first_name = $1 WHERE id = $2", "Jackie", 5)
+		return err
+	})
+	if !errors.Is(err, pgErr) {
+		t.Fatalf("WithTx error = %v, want %v", err, pgErr)
+	}
+	if attempts != 2 {
+		t.Fatalf("expected fn to run 2 times (1 retry), ran %d", attempts)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestManager_WithTx_NestedUsesSavepoint(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("RELEASE SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	mgr := NewManager(db)
+	err = mgr.WithTx(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		return mgr.WithTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, "UPDATE users SET first_name = $1 WHERE id = $2", "Jackie", 5)
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatalf("WithTx returned error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
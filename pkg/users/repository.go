@@ -0,0 +1,175 @@
+// Package users holds the data-access layer for the "users" table, kept
+// separate from main.go so the query building and scanning logic can be
+// unit tested without a live database.
+package users
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/gustavNdamukong/golang-db-connect-demo/pkg/dbrunner"
+)
+
+// Column identifies a users table column that FindBy is allowed to filter
+// on. squirrel treats sq.Eq map keys as raw SQL identifiers rather than
+// placeholder values, so FindBy must only ever be called with one of these
+// rather than an arbitrary caller-supplied string.
+type Column string
+
+const (
+	ColumnID        Column = "id"
+	ColumnFirstName Column = "first_name"
+	ColumnLastName  Column = "last_name"
+)
+
+// queryableColumns is the allowlist FindBy checks column against before it
+// ever reaches squirrel.
+var queryableColumns = map[Column]bool{
+	ColumnID:        true,
+	ColumnFirstName: true,
+	ColumnLastName:  true,
+}
+
+// UserRepository is the set of operations the rest of the app needs
+// against the users table. It is an interface (rather than exposing the
+// concrete type below directly) so callers can swap in a test double.
+// Every method takes a context.Context so callers can cancel or bound a
+// call; the repository itself also applies a per-call deadline and retries
+// transient errors - see pkg/dbrunner.
+type UserRepository interface {
+	List(ctx context.Context) ([]User, error)
+	Get(ctx context.Context, id int) (User, error)
+	Create(ctx context.Context, u User) (int, error)
+	Update(ctx context.Context, u User) error
+	Delete(ctx context.Context, id int) error
+	// FindBy filters the users table on one of the known Column values,
+	// e.g. FindBy(ctx, ColumnLastName, "Brown").
+	FindBy(ctx context.Context, column Column, value interface{}) ([]User, error)
+}
+
+// repository is the dbrunner.Runner backed implementation of
+// UserRepository. It builds every query through squirrel instead of string
+// concatenation so placeholders are always applied, even as filters are
+// composed.
+type repository struct {
+	runner  *dbrunner.Runner
+	builder sq.StatementBuilderType
+}
+
+// NewRepository wraps an existing *sql.DB connection (e.g. one opened with
+// sql.Open("pgx", ...)) in a UserRepository. Any dbrunner.Option is passed
+// through to the Runner that executes its queries.
+func NewRepository(db *sql.DB, opts ...dbrunner.Option) UserRepository {
+	return &repository{
+		runner:  dbrunner.New(db, opts...),
+		builder: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+func (r *repository) List(ctx context.Context) ([]User, error) {
+	rows, err := r.builder.
+		Select("id", "first_name", "last_name").
+		From("users").
+		OrderBy("id").
+		RunWith(r.runner).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []User
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.FirstName, &u.LastName); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+func (r *repository) Get(ctx context.Context, id int) (User, error) {
+	var u User
+	err := r.builder.
+		Select("id", "first_name", "last_name").
+		From("users").
+		Where(sq.Eq{"id": id}).
+		RunWith(r.runner).
+		QueryRowContext(ctx).
+		Scan(&u.ID, &u.FirstName, &u.LastName)
+	return u, err
+}
+
+func (r *repository) Create(ctx context.Context, u User) (int, error) {
+	var id int
+	err := r.builder.
+		Insert("users").
+		Columns("first_name", "last_name").
+		Values(u.FirstName, u.LastName).
+		Suffix("RETURNING id").
+		RunWith(r.runner).
+		QueryRowContext(ctx).
+		Scan(&id)
+	return id, err
+}
+
+// Update sets only the fields the caller actually supplied (FirstName/
+// LastName are sql.NullString, so Valid tells us that apart from a
+// deliberate NULL); fields left at their zero value are left untouched
+// rather than overwritten with NULL.
+func (r *repository) Update(ctx context.Context, u User) error {
+	b := r.builder.Update("users")
+	if u.FirstName.Valid {
+		b = b.Set("first_name", u.FirstName)
+	}
+	if u.LastName.Valid {
+		b = b.Set("last_name", u.LastName)
+	}
+
+	_, err := b.
+		Where(sq.Eq{"id": u.ID}).
+		RunWith(r.runner).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *repository) Delete(ctx context.Context, id int) error {
+	_, err := r.builder.
+		Delete("users").
+		Where(sq.Eq{"id": id}).
+		RunWith(r.runner).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *repository) FindBy(ctx context.Context, column Column, value interface{}) ([]User, error) {
+	if !queryableColumns[column] {
+		return nil, fmt.Errorf("users: FindBy: not a queryable column: %q", column)
+	}
+
+	rows, err := r.builder.
+		Select("id", "first_name", "last_name").
+		From("users").
+		Where(sq.Eq{string(column): value}).
+		OrderBy("id").
+		RunWith(r.runner).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []User
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.FirstName, &u.LastName); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,160 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: true}
+}
+
+func newMockRepository(t *testing.T) (*repository, sqlmock.Sqlmock, func()) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+
+	repo := NewRepository(db).(*repository)
+	return repo, mock, func() { db.Close() }
+}
+
+func TestRepository_List(t *testing.T) {
+	repo, mock, closeFn := newMockRepository(t)
+	defer closeFn()
+
+	rows := sqlmock.NewRows([]string{"id", "first_name", "last_name"}).
+		AddRow(1, "Jack", "Brown").
+		AddRow(2, "Jane", nil)
+
+	mock.ExpectQuery("SELECT id, first_name, last_name FROM users ORDER BY id").
+		WillReturnRows(rows)
+
+	got, err := repo.List(context.Background())
+	if err != nil {
+		t.Fatalf("List() returned error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 users, got %d", len(got))
+	}
+	if got[0].FirstName.String != "Jack" {
+		t.Errorf("expected first user Jack, got %q", got[0].FirstName.String)
+	}
+	if got[1].LastName.Valid {
+		t.Errorf("expected second user's last name to be NULL")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestRepository_Get(t *testing.T) {
+	repo, mock, closeFn := newMockRepository(t)
+	defer closeFn()
+
+	rows := sqlmock.NewRows([]string{"id", "first_name", "last_name"}).
+		AddRow(5, "Jackie", "Brown")
+
+	mock.ExpectQuery("SELECT id, first_name, last_name FROM users WHERE id = \\$1").
+		WithArgs(5).
+		WillReturnRows(rows)
+
+	got, err := repo.Get(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("Get() returned error: %v", err)
+	}
+	if got.FirstName.String != "Jackie" {
+		t.Errorf("expected Jackie, got %q", got.FirstName.String)
+	}
+}
+
+func TestRepository_Create(t *testing.T) {
+	repo, mock, closeFn := newMockRepository(t)
+	defer closeFn()
+
+	mock.ExpectQuery("INSERT INTO users \\(first_name,last_name\\) VALUES \\(\\$1,\\$2\\) RETURNING id").
+		WithArgs("Jack", "Brown").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+
+	id, err := repo.Create(context.Background(), User{
+		FirstName: nullString("Jack"),
+		LastName:  nullString("Brown"),
+	})
+	if err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	if id != 6 {
+		t.Errorf("expected id 6, got %d", id)
+	}
+}
+
+func TestRepository_Update(t *testing.T) {
+	repo, mock, closeFn := newMockRepository(t)
+	defer closeFn()
+
+	mock.ExpectExec("UPDATE users SET first_name = \\$1 WHERE id = \\$2").
+		WithArgs("Jackie", 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.Update(context.Background(), User{ID: 5, FirstName: nullString("Jackie")})
+	if err != nil {
+		t.Fatalf("Update() returned error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestRepository_FindBy(t *testing.T) {
+	repo, mock, closeFn := newMockRepository(t)
+	defer closeFn()
+
+	rows := sqlmock.NewRows([]string{"id", "first_name", "last_name"}).
+		AddRow(2, "Jane", "Brown")
+
+	mock.ExpectQuery("SELECT id, first_name, last_name FROM users WHERE last_name = \\$1 ORDER BY id").
+		WithArgs("Brown").
+		WillReturnRows(rows)
+
+	got, err := repo.FindBy(context.Background(), ColumnLastName, "Brown")
+	if err != nil {
+		t.Fatalf("FindBy() returned error: %v", err)
+	}
+	if len(got) != 1 || got[0].FirstName.String != "Jane" {
+		t.Fatalf("expected one user Jane, got %+v", got)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestRepository_FindBy_RejectsUnknownColumn(t *testing.T) {
+	repo, _, closeFn := newMockRepository(t)
+	defer closeFn()
+
+	_, err := repo.FindBy(context.Background(), Column("id=1; DROP TABLE users; --"), "Brown")
+	if err == nil {
+		t.Fatal("expected FindBy to reject a non-whitelisted column, got nil error")
+	}
+}
+
+func TestRepository_Delete(t *testing.T) {
+	repo, mock, closeFn := newMockRepository(t)
+	defer closeFn()
+
+	mock.ExpectExec("DELETE FROM users WHERE id = \\$1").
+		WithArgs(6).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := repo.Delete(context.Background(), 6); err != nil {
+		t.Fatalf("Delete() returned error: %v", err)
+	}
+}
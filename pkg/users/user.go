@@ -0,0 +1,12 @@
+package users
+
+import "database/sql"
+
+// User is the row shape for the "users" table. The name fields use
+// sql.NullString because the column is nullable in the schema this demo
+// targets - scanning a NULL into a plain string fails with an error.
+type User struct {
+	ID        int
+	FirstName sql.NullString
+	LastName  sql.NullString
+}
@@ -1,15 +1,28 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	"time"
 
+	"github.com/jackc/pgx/v4/pgxpool"
 	_ "github.com/jackc/pgx/v4/stdlib"
+
+	"github.com/gustavNdamukong/golang-db-connect-demo/pkg/dbpool"
+	"github.com/gustavNdamukong/golang-db-connect-demo/pkg/migrate"
+	"github.com/gustavNdamukong/golang-db-connect-demo/pkg/store"
+	"github.com/gustavNdamukong/golang-db-connect-demo/pkg/tx"
+	"github.com/gustavNdamukong/golang-db-connect-demo/pkg/users"
 )
 
+const dsn = "host=localhost port=5432 dbname=test_connect user=user password="
+
 func main() {
-	// connect to DB
+	// pick a backend
 	//---------------------------------------------
 	/*
 	  In go there are a couple of packages to connect to DB.
@@ -17,83 +30,77 @@ func main() {
 	  Another even better one by a guy jackc & it's called pgx
 	  	https://github.com/jackc/pgx
 
-	  -Install it like so in your CLI: 'go get github.com/jackc/pgx/v4'
-	  	where '/v4' specifies the version to install.
+	  This demo can now run the exact same CRUD flow against postgres
+	  two different ways:
 
-	  -Obviously, you need to have installed postgres & eg a DB client like DBeaver
+	    -backend=sql  uses database/sql + pgx/v4/stdlib (the original path,
+	                   works with any driver that implements database/sql)
+	    -backend=pgx   uses pgxpool.Pool directly, giving up the
+	                   database/sql abstraction in exchange for pgx-only
+	                   features like CopyFrom bulk inserts
 
-	  -Create a DB eg 'test_connect' in your DB system to test this with.
+	  Both are wrapped behind the same store.Store interface (pkg/store), so
+	  everything below this point doesn't know or care which one it's
+	  talking to.
 
-	  -We're going to use the default sql package which is part of go. But it's possible
-	   to use different drivers for specific DB types.
-	*/
-	conn, err := sql.Open("pgx", "host=localhost port=5432 dbname=test_connect user=user password=")
-	if err != nil {
-		log.Fatal(fmt.Sprintf("Unable to connect: %v\n", err))
-	}
-	defer conn.Close()
-
-	log.Println("Conected to database")
-
-	/*
-		-If you were in production, the Open() host param will contain your remote host name
-		-port 5432 is the default sql port
-		-user has to be the name of your user account on your computer.
-		-The 'defer' line makes sure the DB connection will be closed when it has done its job
-			(THIS IS ABSOLUTELY CRUCIAL FOR PERFORMANCE)
-		-Run this file to connect to the DB like so:
-			//navigate to the dir with the main.go file, then run the file
-			cd test_connect
-			go run main.go
-		-If you run it and get an  error like 'Unable to connect: sql: unknown driver "pgx" (forgotten import?)'
-			it means you had to tell this package which driver you are using, so add this to your import line:
-
-				_ "github.com/jackc/pgx/v4/stdlib"
-
-		-When we connect to postgres using sql.Open() like this, using 'pgx', it returns a pool of DB connections
-		 that we can choose from.
+	  -Obviously, you need to have installed postgres & eg a DB client like DBeaver
+	  -Create a DB eg 'test_connect' in your DB system to test this with.
 	*/
+	backend := flag.String("backend", "sql", `which Store backend to use: "sql" or "pgx"`)
+	metricsAddr := flag.String("metrics-addr", ":9100", "address to serve /metrics on (sql backend only)")
+	flag.Parse()
 
-	// test my connection
+	// bootstrap schema
 	//---------------------------------------------
-	err = conn.Ping()
+	// pkg/migrate ships the users table as migration 0001_create_users,
+	// embedded into the binary via go:embed, so this works against a
+	// brand new database - no more hand-running CREATE TABLE first.
+	if err := bootstrapSchema(); err != nil {
+		log.Fatal(fmt.Sprintf("Unable to run migrations: %v\n", err))
+	}
+
+	s, conn, leakDetector, closeFn, err := newStore(*backend, *metricsAddr)
 	if err != nil {
-		log.Fatal("Cannot connect to database!")
+		log.Fatal(fmt.Sprintf("Unable to connect: %v\n", err))
 	}
+	defer closeFn()
 
-	log.Println("Pinged database")
+	log.Println("Conected to database using backend:", *backend)
+
+	// Every store call below takes a context.Context, which the sql backend
+	// uses to apply a per-call deadline and retry transient errors (see
+	// pkg/dbrunner) and the pgx backend passes straight through to the pool.
+	ctx := context.Background()
 
 	// get rows from table
 	//---------------------------------------------
-	err = getAllRows(conn)
-	if err != nil {
+	if err := printAllUsers(ctx, s); err != nil {
 		log.Fatal(err)
 	}
 
 	// insert row
 	//---------------------------------------------
-	// Backticks are great for writing queries coz they allow u write queries on
-	// multiple lines & that can make them readable
-	// we use an underscore on the 2nd line coz here we are doing an insert, so we ignore the result
-	insertQuery := `INSERT INTO users (first_name, last_name) VALUES ($1, $2)`
-	_, err = conn.Exec(insertQuery, "Jack", "Brown")
+	id, err := s.Create(ctx, users.User{
+		FirstName: sql.NullString{String: "Jack", Valid: true},
+		LastName:  sql.NullString{String: "Brown", Valid: true},
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
-	log.Println("Inserted a row!")
+	log.Println("Inserted a row with id", id)
 
 	// get rows from table again (so we can notice the update from above insert query)
 	//---------------------------------------------
-	err = getAllRows(conn)
-	if err != nil {
+	if err := printAllUsers(ctx, s); err != nil {
 		log.Fatal(err)
 	}
 
 	// update a row
 	//---------------------------------------------
-	updateQuery := `UPDATE users SET first_name = $1 
-		WHERE id = $2`
-	_, err = conn.Exec(updateQuery, "Jackie", 5)
+	err = s.Update(ctx, users.User{
+		ID:        5,
+		FirstName: sql.NullString{String: "Jackie", Valid: true},
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -101,79 +108,181 @@ func main() {
 
 	// get rows from table again (so we can notice the update from above query)
 	//---------------------------------------------
-	err = getAllRows(conn)
-	if err != nil {
+	if err := printAllUsers(ctx, s); err != nil {
 		log.Fatal(err)
 	}
 
 	// get one row by id
-	// Notice that unline with inserts & updates where we use conn.Exec() to run the query,
-	// when selecting just one row where we are sure only one row will be returned, we use
-	// conn.QueryRow()
-	// Also, when selecting with QueryRow, the error doesn't come with the query
-	// you only get that when you try to scan (extract) the data from the returned row
 	//---------------------------------------------
-	fetchOneRowQuery := `SELECT id, first_name, last_name FROM users WHERE id = $1`
-	var firstName, lastName string
-	var id int
-	row := conn.QueryRow(fetchOneRowQuery, 1)
-	err = row.Scan(&id, &firstName, &lastName)
+	u, err := s.Get(ctx, 1)
 	if err != nil {
 		log.Fatal(err)
 	}
-	log.Println("QueryRow returns", id, firstName, lastName)
+	log.Println("Get(1) returns", u.ID, u.FirstName.String, u.LastName.String)
 
 	// delete a row
 	//---------------------------------------------
-	deleteQuery := `DELETE FROM users WHERE id = $1`
-	_, err = conn.Exec(deleteQuery, 6)
-	if err != nil {
+	if err := s.Delete(ctx, 6); err != nil {
 		log.Fatal(err)
 	}
 	log.Println("Deleted a row!")
 
 	// get rows from table again (so we can notice the update from above delete query)
 	//---------------------------------------------
-	err = getAllRows(conn)
-	if err != nil {
+	if err := printAllUsers(ctx, s); err != nil {
 		log.Fatal(err)
 	}
-}
 
-// conn is actually a pointer to sql.DB
-// Just like you always need to close a connection when you connect to a DB,
-// 	every time you are running a query against a DB which can return more than one row,
-//	you have to close the DB connection-hence use 'defer rows.Close()' after the query line
-// If you don't do this, your DB will gradually run out of resources & die in a matter of hours or daya
+	// transactional demo
+	//---------------------------------------------
+	// conn is only non-nil for the sql backend (pkg/tx works against
+	// *sql.DB), so this section is skipped for -backend=pgx.
+	if conn != nil {
+		if err := runTransactionalDemo(ctx, tx.NewManager(conn)); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// leak detector demo
+	//---------------------------------------------
+	// leakDetector is only non-nil for the sql backend, same as conn.
+	if leakDetector != nil {
+		if err := runLeakDetectorDemo(ctx, leakDetector); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
 
-func getAllRows(conn *sql.DB) error {
-	rows, err := conn.Query("SELECT id, first_name, last_name FROM users")
+// runLeakDetectorDemo runs a query through leakDetector instead of straight
+// through the store, and promptly closes the rows it gets back - showing
+// the happy path where Close stops the leak timer before it ever fires.
+// Forgetting that Close is exactly the bug this type exists to catch.
+func runLeakDetectorDemo(ctx context.Context, leakDetector *dbpool.LeakDetector) error {
+	rows, err := leakDetector.QueryContext(ctx, `SELECT id, first_name, last_name FROM users ORDER BY id`)
 	if err != nil {
-		log.Println(err)
 		return err
 	}
 	defer rows.Close()
 
-	var firstName, lastName string
-	var id int
-
+	var n int
 	for rows.Next() {
-		// scan the data that you've queried from DB into your vars
-		// scan them in the same order as you've queried them from the DB
-		err := rows.Scan(&id, &firstName, &lastName)
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	log.Println("leak detector demo: read", n, "rows with no leak")
+	return nil
+}
+
+// runTransactionalDemo inserts then immediately corrects a row's name as a
+// single transactional unit: the insert and the correction either both
+// land or neither does. The correction runs through a nested WithTx call,
+// which the manager recognises (via the ctx it was handed) as happening
+// inside the outer transaction already, so it opens a SAVEPOINT instead of
+// a second BEGIN.
+func runTransactionalDemo(ctx context.Context, txMgr *tx.Manager) error {
+	return txMgr.WithTx(ctx, func(ctx context.Context, outer *sql.Tx) error {
+		var id int
+		err := outer.QueryRowContext(ctx,
+			`INSERT INTO users (first_name, last_name) VALUES ($1, $2) RETURNING id`,
+			"Jack", "Brown",
+		).Scan(&id)
 		if err != nil {
-			log.Println(err)
 			return err
 		}
-		fmt.Println("Record is", id, firstName, lastName)
+		log.Println("tx demo: inserted row with id", id)
+
+		return txMgr.WithTx(ctx, func(ctx context.Context, inner *sql.Tx) error {
+			_, err := inner.ExecContext(ctx,
+				`UPDATE users SET first_name = $1 WHERE id = $2`, "Jackie", id)
+			if err != nil {
+				return err
+			}
+			log.Println("tx demo: corrected row", id, "to Jackie, within the same transaction")
+			return nil
+		})
+	})
+}
+
+// bootstrapSchema applies every pending migration in pkg/migrate/sql
+// against dsn, independent of which Store backend the demo goes on to use.
+func bootstrapSchema() error {
+	conn, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return err
 	}
+	defer conn.Close()
+
+	return migrate.New(conn, migrate.FS, "sql").Up(context.Background())
+}
+
+// newStore opens a connection using the driver the given backend name
+// selects and wraps it as a store.Store, returning a func to release it.
+// For the sql backend it also tunes the pool, starts a background health
+// check, serves its stats on metricsAddr (see pkg/dbpool), and arms a rows
+// leak detector; it returns the underlying *sql.DB and *dbpool.LeakDetector
+// so callers can also drive them directly (pkg/tx, the leak detector demo
+// in main); the pgx backend has no equivalent, so it returns both as nil.
+func newStore(backend, metricsAddr string) (store.Store, *sql.DB, *dbpool.LeakDetector, func(), error) {
+	switch backend {
+	case "pgx":
+		pool, err := pgxpool.Connect(context.Background(), dsn)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		return store.NewPgxStore(pool), nil, nil, pool.Close, nil
 
-	// check for errors again here as a safe practice-incase any error was not caught in
-	// the for block above
-	if err = rows.Err(); err != nil {
-		log.Fatal("Error scanning rows", err)
+	case "sql":
+		conn, err := sql.Open("pgx", dsn)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		if err := conn.Ping(); err != nil {
+			conn.Close()
+			return nil, nil, nil, nil, fmt.Errorf("cannot connect to database: %w", err)
+		}
+
+		dbpool.DefaultPoolConfig().Apply(conn)
+
+		checkCtx, stopChecks := context.WithCancel(context.Background())
+		checker := dbpool.NewHealthChecker(conn, 10*time.Second)
+		go checker.Run(checkCtx)
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", checker.MetricsHandler())
+		metricsServer := &http.Server{Addr: metricsAddr, Handler: mux}
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Println("dbpool: metrics server stopped:", err)
+			}
+		}()
+
+		leakDetector := dbpool.NewLeakDetector(conn, 5*time.Second, true)
+
+		return store.NewSQLStore(conn), conn, leakDetector, func() {
+			stopChecks()
+			metricsServer.Close()
+			conn.Close()
+		}, nil
+
+	default:
+		return nil, nil, nil, nil, fmt.Errorf("unknown backend %q, want \"sql\" or \"pgx\"", backend)
+	}
+}
+
+// printAllUsers lists every row in the users table through the store
+// and prints it, mirroring what the old hand-rolled getAllRows did.
+func printAllUsers(ctx context.Context, s store.Store) error {
+	all, err := s.List(ctx)
+	if err != nil {
+		log.Println(err)
+		return err
 	}
 
+	for _, u := range all {
+		fmt.Println("Record is", u.ID, u.FirstName.String, u.LastName.String)
+	}
 	fmt.Println("------------------------------------")
 
 	return nil